@@ -0,0 +1,172 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"time"
+
+	madmin "github.com/minio/madmin-go"
+)
+
+// This file wraps admin APIs of newer minio releases that madmin-go doesn't
+// support yet.
+
+// Supported values of the policy_api config key.
+const (
+	policyAPISet    = "set"
+	policyAPIAttach = "attach"
+)
+
+// policyAssociationReq is the body of the policy attach and detach requests
+// of newer minio releases, which madmin-go doesn't wrap yet.
+type policyAssociationReq struct {
+	Policies []string `json:"policies"`
+	User     string   `json:"user,omitempty"`
+}
+
+// attachPolicies adds policies to the ones of a user.
+func attachPolicies(ctx context.Context, client *madmin.AdminClient, username string, policies []string) error {
+	return changePolicies(ctx, client, "attach", username, policies)
+}
+
+// detachPolicies removes policies from a user, keeping the others.
+func detachPolicies(ctx context.Context, client *madmin.AdminClient, username string, policies []string) error {
+	return changePolicies(ctx, client, "detach", username, policies)
+}
+
+// changePolicies calls the builtin IDP policy attach or detach API. Policies
+// that are already attached or detached are not an error.
+func changePolicies(ctx context.Context, client *madmin.AdminClient, operation, username string, policies []string) error {
+	if len(policies) == 0 {
+		return nil
+	}
+	data, err := json.Marshal(policyAssociationReq{Policies: policies, User: username})
+	if err != nil {
+		return err
+	}
+	_, secretKey := client.GetAccessAndSecretKey()
+	content, err := madmin.EncryptData(secretKey, data)
+	if err != nil {
+		return err
+	}
+
+	resp, err := client.ExecuteMethod(ctx, http.MethodPost, madmin.RequestData{
+		RelPath: "/" + madmin.AdminAPIVersion + "/idp/builtin/policy/" + operation,
+		Content: content,
+	})
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusOK {
+		return nil
+	}
+	err = responseError(resp, "failed to "+operation+" policies")
+	if madmin.ToErrorResponse(err).Code == "XMinioAdminPolicyChangeAlreadyApplied" {
+		return nil
+	} else if unsupportedAPI(resp, err) {
+		return fmt.Errorf("%w: %v", featurePolicyAttach.unsupported("this minio server"), err)
+	}
+	return err
+}
+
+// addServiceAccountReq extends madmin.AddServiceAccountReq with the
+// expiration and description of newer minio releases.
+type addServiceAccountReq struct {
+	madmin.AddServiceAccountReq
+	Expiration  *time.Time `json:"expiration,omitempty"`
+	Description string     `json:"description,omitempty"`
+}
+
+// addServiceAccount creates a service account that expires at expiration,
+// or never if it is zero, described by description. Minio releases without
+// service account expiry or descriptions ignore them.
+func addServiceAccount(ctx context.Context, client *madmin.AdminClient, opts madmin.AddServiceAccountReq, expiration time.Time, description string) (madmin.Credentials, error) {
+	if expiration.IsZero() && description == "" {
+		return client.AddServiceAccount(ctx, opts)
+	}
+	req := addServiceAccountReq{AddServiceAccountReq: opts, Description: description}
+	if !expiration.IsZero() {
+		req.Expiration = &expiration
+	}
+	data, err := json.Marshal(req)
+	if err != nil {
+		return madmin.Credentials{}, err
+	}
+	_, secretKey := client.GetAccessAndSecretKey()
+	content, err := madmin.EncryptData(secretKey, data)
+	if err != nil {
+		return madmin.Credentials{}, err
+	}
+
+	resp, err := client.ExecuteMethod(ctx, http.MethodPut, madmin.RequestData{
+		RelPath: "/" + madmin.AdminAPIVersion + "/add-service-account",
+		Content: content,
+	})
+	if err != nil {
+		return madmin.Credentials{}, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return madmin.Credentials{}, responseError(resp, "failed to create service account")
+	}
+
+	data, err = madmin.DecryptData(secretKey, resp.Body)
+	if err != nil {
+		return madmin.Credentials{}, err
+	}
+	var serviceAccount madmin.AddServiceAccountResp
+	if err := json.Unmarshal(data, &serviceAccount); err != nil {
+		return madmin.Credentials{}, err
+	}
+	return serviceAccount.Credentials, nil
+}
+
+// updateServiceAccountExpiration moves the expiry of a service account, see
+// addServiceAccount.
+func updateServiceAccountExpiration(ctx context.Context, client *madmin.AdminClient, accessKey string, expiration time.Time) error {
+	data, err := json.Marshal(struct {
+		NewExpiration *time.Time `json:"newExpiration,omitempty"`
+	}{&expiration})
+	if err != nil {
+		return err
+	}
+	_, secretKey := client.GetAccessAndSecretKey()
+	content, err := madmin.EncryptData(secretKey, data)
+	if err != nil {
+		return err
+	}
+
+	resp, err := client.ExecuteMethod(ctx, http.MethodPost, madmin.RequestData{
+		RelPath:     "/" + madmin.AdminAPIVersion + "/update-service-account",
+		QueryValues: url.Values{"accessKey": []string{accessKey}},
+		Content:     content,
+	})
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusNoContent {
+		return responseError(resp, "failed to update service account")
+	}
+	return nil
+}
+
+// responseError decodes the error response of a failed admin request. The
+// madmin.ErrorResponse is returned as is, so its Code can be checked.
+func responseError(resp *http.Response, msg string) error {
+	body, err := ioutil.ReadAll(io.LimitReader(resp.Body, 100<<10))
+	if err != nil {
+		return fmt.Errorf("%s: %w", msg, err)
+	}
+	errResp := madmin.ErrorResponse{}
+	if err := json.Unmarshal(body, &errResp); err != nil || errResp.Code == "" {
+		return fmt.Errorf("%s: %s", msg, resp.Status)
+	}
+	return errResp
+}
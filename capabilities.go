@@ -0,0 +1,97 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	madmin "github.com/minio/madmin-go"
+)
+
+// serverFeature is a plugin feature relying on admin APIs that older minio
+// releases don't have.
+type serverFeature struct {
+	name string
+	// minRelease is the tag of the first minio release with the APIs.
+	minRelease string
+}
+
+var (
+	featurePolicyAttach    = serverFeature{name: "policy_api=attach", minRelease: "RELEASE.2023-03-20T20-16-18Z"}
+	featureSiteReplication = serverFeature{name: "site_replication", minRelease: "RELEASE.2021-11-24T23-19-33Z"}
+)
+
+// unsupported returns the error for a server too old for the feature.
+func (f serverFeature) unsupported(server string) error {
+	return fmt.Errorf("%s is not supported by %s, it requires minio %s or newer", f.name, server, f.minRelease)
+}
+
+// releaseTime returns the build time a release tag like
+// RELEASE.2023-03-20T20-16-18Z stands for.
+func releaseTime(tag string) (time.Time, error) {
+	return time.Parse("RELEASE.2006-01-02T15-04-05Z", tag)
+}
+
+// checkServerVersion makes sure every server of the deployment is recent
+// enough for the configured features, so they fail in Initialize rather than
+// with opaque errors on first use. Servers report their build time as
+// version. Development builds without one, and credentials lacking the
+// admin:ServerInfo permission, skip the check. Callers must hold mux.
+func (minio *Minio) checkServerVersion(ctx context.Context, features []serverFeature) error {
+	if len(features) == 0 {
+		return nil
+	}
+	client, err := minio.getClient()
+	if err != nil {
+		return err
+	}
+	ctx, cancel := minio.withTimeout(ctx)
+	defer cancel()
+	info, err := client.ServerInfo(ctx)
+	if madmin.ToErrorResponse(err).Code == "AccessDenied" {
+		minio.logger.Warn("not allowed to read the server version, skipping the version check", "error", err)
+		return nil
+	} else if err != nil {
+		return fmt.Errorf("failed to check server version: %w", err)
+	}
+
+	var oldest time.Time
+	oldestVersion := ""
+	for _, server := range info.Servers {
+		built, err := time.Parse(time.RFC3339, server.Version)
+		if err != nil {
+			minio.logger.Debug("unknown server version, skipping it in the version check", "endpoint", server.Endpoint, "version", server.Version)
+			continue
+		}
+		if oldest.IsZero() || built.Before(oldest) {
+			oldest = built
+			oldestVersion = server.Version
+		}
+	}
+	if oldest.IsZero() {
+		return nil
+	}
+	for _, feature := range features {
+		required, err := releaseTime(feature.minRelease)
+		if err != nil {
+			return err
+		}
+		if oldest.Before(required) {
+			return feature.unsupported("minio " + oldestVersion)
+		}
+	}
+	return nil
+}
+
+// unsupportedAPI reports whether a failed admin request was rejected because
+// the server doesn't know the API, as older releases do for newer calls.
+func unsupportedAPI(resp *http.Response, err error) bool {
+	switch resp.StatusCode {
+	case http.StatusNotFound, http.StatusMethodNotAllowed, http.StatusNotImplemented:
+		return true
+	}
+	errResp := madmin.ToErrorResponse(err)
+	return errResp.Code == "XMinioAdminVersionMismatch" || strings.HasPrefix(errResp.Message, "Unknown API request")
+}
@@ -0,0 +1,141 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"strings"
+
+	"github.com/hashicorp/go-hclog"
+	dbplugin "github.com/hashicorp/vault/sdk/database/dbplugin/v5"
+)
+
+// command is an operator subcommand of the plugin binary, for tasks vault
+// has no endpoint for. Commands connect to minio with the connection config
+// of the vault database mount, read from a JSON file.
+type command struct {
+	// args names the positional arguments for the usage message.
+	args []string
+	run  func(ctx context.Context, minio *Minio, args []string) (interface{}, error)
+}
+
+var commands = map[string]command{
+	"effective-policy": {args: []string{"username"}, run: func(ctx context.Context, minio *Minio, args []string) (interface{}, error) {
+		document, err := minio.effectivePolicy(ctx, args[0])
+		if err != nil {
+			return nil, err
+		}
+		return json.RawMessage(document), nil
+	}},
+	"list-managed-users": {run: func(ctx context.Context, minio *Minio, args []string) (interface{}, error) {
+		users, err := minio.listManagedUsers(ctx)
+		if err != nil {
+			return nil, err
+		}
+		return users, nil
+	}},
+	"ping": {run: func(ctx context.Context, minio *Minio, args []string) (interface{}, error) {
+		return nil, minio.checkConnection(ctx)
+	}},
+	"selftest": {run: func(ctx context.Context, minio *Minio, args []string) (interface{}, error) {
+		steps, err := minio.selfTest(ctx)
+		if len(steps) == 0 {
+			return nil, err
+		}
+		type result struct {
+			Name     string `json:"name"`
+			Duration string `json:"duration"`
+			Error    string `json:"error,omitempty"`
+		}
+		results := []result{}
+		for _, step := range steps {
+			r := result{Name: step.Name, Duration: step.Duration.String()}
+			if step.Err != nil {
+				r.Error = step.Err.Error()
+			}
+			results = append(results, r)
+		}
+		return results, err
+	}},
+}
+
+// commandUsage returns the usage line of a subcommand.
+func commandUsage(name string) string {
+	usage := []string{os.Args[0], name, "-config FILE"}
+	for _, arg := range commands[name].args {
+		usage = append(usage, strings.ToUpper(arg))
+	}
+	return strings.Join(usage, " ")
+}
+
+// runCommand runs the subcommand name with its command line arguments. The
+// result of the command, if any, is written to stdout as JSON, even if it
+// failed, so partial results like self test steps are not lost.
+func runCommand(name string, args []string, stdin io.Reader, stdout io.Writer) error {
+	cmd := commands[name]
+	flags := flag.NewFlagSet(name, flag.ContinueOnError)
+	configPath := flags.String("config", "", "JSON file with the connection config of the vault mount, - for stdin")
+	flags.Usage = func() {
+		fmt.Fprintf(flags.Output(), "usage: %s\n", commandUsage(name))
+		flags.PrintDefaults()
+	}
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if *configPath == "" || flags.NArg() != len(cmd.args) {
+		flags.Usage()
+		return flag.ErrHelp
+	}
+	config, err := readCommandConfig(*configPath, stdin)
+	if err != nil {
+		return err
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	logger := hclog.New(&hclog.LoggerOptions{
+		Name:   "minio",
+		Level:  hclog.Warn,
+		Output: os.Stderr,
+	})
+	db := &Minio{logger: logger, shutdown: make(chan struct{})}
+	defer db.Close()
+	// Connection problems are reported by the command itself.
+	if _, err := db.Initialize(ctx, dbplugin.InitializeRequest{Config: config}); err != nil {
+		return err
+	}
+
+	result, err := cmd.run(ctx, db, flags.Args())
+	if result != nil {
+		encoder := json.NewEncoder(stdout)
+		encoder.SetIndent("", "  ")
+		if encodeErr := encoder.Encode(result); err == nil {
+			err = encodeErr
+		}
+	}
+	return err
+}
+
+// readCommandConfig reads the connection config of a subcommand from the
+// JSON file path, or from stdin if it is "-".
+func readCommandConfig(path string, stdin io.Reader) (map[string]interface{}, error) {
+	var data []byte
+	var err error
+	if path == "-" {
+		data, err = io.ReadAll(stdin)
+	} else {
+		data, err = os.ReadFile(path)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read config: %w", err)
+	}
+	config := map[string]interface{}{}
+	if err := json.Unmarshal(data, &config); err != nil {
+		return nil, fmt.Errorf("config must be a JSON object: %w", err)
+	}
+	return config, nil
+}
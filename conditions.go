@@ -0,0 +1,149 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/minio/pkg/bucket/policy"
+	"github.com/minio/pkg/bucket/policy/condition"
+	iampolicy "github.com/minio/pkg/iam/policy"
+)
+
+// conditionOperators lists the condition operators minio evaluates, by the
+// type of value they compare.
+var conditionOperators = map[string]string{
+	"StringEquals":              "string",
+	"StringNotEquals":           "string",
+	"StringEqualsIgnoreCase":    "string",
+	"StringNotEqualsIgnoreCase": "string",
+	"StringLike":                "string",
+	"StringNotLike":             "string",
+	"BinaryEquals":              "string",
+	"IpAddress":                 "ip",
+	"NotIpAddress":              "ip",
+	"Null":                      "null",
+	"Bool":                      "bool",
+	"NumericEquals":             "numeric",
+	"NumericNotEquals":          "numeric",
+	"NumericLessThan":           "numeric",
+	"NumericLessThanEquals":     "numeric",
+	"NumericGreaterThan":        "numeric",
+	"NumericGreaterThanEquals":  "numeric",
+	"DateEquals":                "date",
+	"DateNotEquals":             "date",
+	"DateLessThan":              "date",
+	"DateLessThanEquals":        "date",
+	"DateGreaterThan":           "date",
+	"DateGreaterThanEquals":     "date",
+}
+
+// conditionQualifiers are the set operator prefixes, as in
+// "ForAnyValue:StringLike".
+var conditionQualifiers = map[string]bool{
+	"ForAllValues": true,
+	"ForAnyValue":  true,
+}
+
+// typedConditionKeys lists the condition keys numeric and date operators can
+// match. Minio accepts these operators on any key, but compares the request
+// values as numbers or dates, so on other keys they never match.
+var typedConditionKeys = map[string][]string{
+	"numeric": {"s3:max-keys", "s3:object-lock-remaining-retention-days"},
+	"date":    {"aws:CurrentTime", "aws:EpochTime", "s3:object-lock-retain-until-date"},
+}
+
+// checkPolicyConditions checks the condition blocks of a policy document if
+// strict_policy_validation is set. Parsing already rejects most malformed
+// conditions, this also catches operators that are accepted but can never
+// match their key and would silently deny every request.
+func (minio *Minio) checkPolicyConditions(name string, document *iampolicy.Policy) error {
+	if !minio.strictPolicyValidation {
+		return nil
+	}
+	for i, statement := range document.Statements {
+		if len(statement.Conditions) == 0 {
+			continue
+		}
+		data, err := json.Marshal(statement.Conditions)
+		if err != nil {
+			return fmt.Errorf("invalid policy %q: %w", name, err)
+		}
+		conditions := map[string]map[string]json.RawMessage{}
+		if err := json.Unmarshal(data, &conditions); err != nil {
+			return fmt.Errorf("invalid policy %q: %w", name, err)
+		}
+		for operator, keys := range conditions {
+			if err := checkConditionOperator(operator, keys); err != nil {
+				return fmt.Errorf("statement %d of policy %q: %w", i, name, err)
+			}
+		}
+	}
+	return nil
+}
+
+// checkConditionOperator checks an operator and the keys it is applied to.
+func checkConditionOperator(operator string, keys map[string]json.RawMessage) error {
+	name := operator
+	if qualifier, rest, found := strings.Cut(operator, ":"); found {
+		if !conditionQualifiers[qualifier] {
+			return fmt.Errorf("unknown condition qualifier %q in %q, must be ForAllValues or ForAnyValue", qualifier, operator)
+		}
+		name = rest
+	}
+	valueType, ok := conditionOperators[name]
+	if !ok {
+		known := make([]string, 0, len(conditionOperators))
+		for operator := range conditionOperators {
+			if strings.EqualFold(operator, name) {
+				return fmt.Errorf("unknown condition operator %q, did you mean %q?", name, operator)
+			}
+			known = append(known, operator)
+		}
+		sort.Strings(known)
+		return fmt.Errorf("unknown condition operator %q, must be one of %s", name, strings.Join(known, ", "))
+	}
+	allowed, typed := typedConditionKeys[valueType]
+	if !typed {
+		return nil
+	}
+	for key := range keys {
+		keyName, _, _ := strings.Cut(key, "/")
+		found := false
+		for _, allowedKey := range allowed {
+			if keyName == allowedKey {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return fmt.Errorf("condition operator %q never matches key %q, it only applies to %s", operator, key, strings.Join(allowed, ", "))
+		}
+	}
+	return nil
+}
+
+// requireSSE returns the policy with a statement denying uploads without
+// server side encryption on every resource it allows s3:PutObject on, for
+// the RequireSSE field of EnsurePolicy.
+func requireSSE(document *iampolicy.Policy) (*iampolicy.Policy, error) {
+	resources := iampolicy.NewResourceSet()
+	for _, statement := range document.Statements {
+		if statement.Effect == policy.Allow && statement.Actions.Match(iampolicy.PutObjectAction) {
+			for resource := range statement.Resources {
+				resources.Add(resource)
+			}
+		}
+	}
+	if len(resources) == 0 {
+		return nil, fmt.Errorf("RequireSSE is set, but the policy allows no s3:PutObject")
+	}
+	missing, err := condition.NewNullFunc(condition.NewKey(condition.S3XAmzServerSideEncryption, ""), true)
+	if err != nil {
+		return nil, err
+	}
+	deny := iampolicy.NewStatement("RequireSSE", policy.Deny, iampolicy.NewActionSet(iampolicy.PutObjectAction), resources, condition.NewFunctions(missing))
+	merged := document.Merge(iampolicy.Policy{Statements: []iampolicy.Statement{deny}})
+	return &merged, nil
+}
@@ -0,0 +1,43 @@
+package main
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestCheckConditionOperator(t *testing.T) {
+	tests := []struct {
+		operator string
+		keys     []string
+		wantErr  string
+	}{
+		{operator: "StringEquals", keys: []string{"aws:username"}},
+		{operator: "ForAnyValue:StringLike", keys: []string{"s3:prefix"}},
+		{operator: "NumericLessThan", keys: []string{"s3:max-keys"}},
+		{operator: "DateGreaterThan", keys: []string{"aws:CurrentTime"}},
+		{operator: "NumericEquals", keys: []string{"s3:object-lock-remaining-retention-days/x"}},
+		{operator: "Bool", keys: []string{"aws:SecureTransport"}},
+		{operator: "stringequals", wantErr: `unknown condition operator "stringequals", did you mean "StringEquals"?`},
+		{operator: "StringContains", wantErr: `unknown condition operator "StringContains", must be one of`},
+		{operator: "ForEach:StringLike", wantErr: `unknown condition qualifier "ForEach"`},
+		{operator: "NumericLessThan", keys: []string{"aws:username"}, wantErr: `condition operator "NumericLessThan" never matches key "aws:username"`},
+		{operator: "DateEquals", keys: []string{"s3:max-keys"}, wantErr: `condition operator "DateEquals" never matches key "s3:max-keys"`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.operator, func(t *testing.T) {
+			keys := map[string]json.RawMessage{}
+			for _, key := range tt.keys {
+				keys[key] = json.RawMessage(`"x"`)
+			}
+			err := checkConditionOperator(tt.operator, keys)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("checkConditionOperator(%q, %q): %v", tt.operator, tt.keys, err)
+				}
+			} else if err == nil || !strings.HasPrefix(err.Error(), tt.wantErr) {
+				t.Errorf("checkConditionOperator(%q, %q) error = %v, want %q", tt.operator, tt.keys, err, tt.wantErr)
+			}
+		})
+	}
+}
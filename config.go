@@ -0,0 +1,153 @@
+package main
+
+import (
+	"crypto/x509"
+	"fmt"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/hashicorp/go-secure-stdlib/strutil"
+	madmin "github.com/minio/madmin-go"
+)
+
+// minioConfig holds the connection config values the admin and S3 clients
+// are built from. It is parsed and validated once by parseMinioConfig, so
+// building a client can't fail on the config anymore.
+type minioConfig struct {
+	endpoints []*url.URL
+	accessKey string
+	secretKey string
+
+	// adminAccessKey and adminSecretKey are the optional credentials for
+	// ensuring policies, see getPolicyClient. validateConfig makes sure
+	// they are set together.
+	adminAccessKey string
+	adminSecretKey string
+
+	caPool          *x509.CertPool
+	skipVerify      bool
+	proxy           func(*http.Request) (*url.URL, error)
+	maxIdleConns    int
+	idleConnTimeout time.Duration
+	headers         http.Header
+	maxRetries      int
+
+	region             string
+	roleARN            string
+	assumeRoleDuration time.Duration
+}
+
+// parseMinioConfig parses the connection related config values.
+func parseMinioConfig(config map[string]interface{}) (*minioConfig, error) {
+	connURL, err := connectionURL(config)
+	if err != nil {
+		return nil, err
+	}
+	endpoints, err := parseEndpoints(connURL)
+	if err != nil {
+		return nil, err
+	}
+	c := &minioConfig{endpoints: endpoints}
+	for k, v := range map[string]*string{"username": &c.accessKey, "password": &c.secretKey} {
+		raw, ok := config[k]
+		if !ok {
+			return nil, fmt.Errorf("%q must be provided", k)
+		}
+		if *v, ok = raw.(string); !ok {
+			return nil, fmt.Errorf("%q must be a string", k)
+		} else if *v == "" {
+			return nil, fmt.Errorf("%q must not be empty", k)
+		}
+	}
+	for k, v := range map[string]*string{
+		"admin_username": &c.adminAccessKey,
+		"admin_password": &c.adminSecretKey,
+		"region":         &c.region,
+		"role_arn":       &c.roleARN,
+	} {
+		if *v, err = strutil.GetString(config, k); err != nil {
+			return nil, fmt.Errorf("failed to retrieve %s: %w", k, err)
+		}
+	}
+
+	if c.skipVerify, err = getBool(config, "tls_skip_verify"); err != nil {
+		return nil, err
+	}
+	if c.caPool, err = loadCAPool(config); err != nil {
+		return nil, err
+	}
+	if c.proxy, err = proxyFunc(config); err != nil {
+		return nil, err
+	}
+	if c.headers, err = extraHeaders(config); err != nil {
+		return nil, err
+	}
+
+	// Unset values keep the defaults of the madmin transport. All requests
+	// go to the same few hosts, so the per host limit is the one that
+	// matters.
+	defaults, ok := madmin.DefaultTransport(false).(*http.Transport)
+	if !ok {
+		return nil, fmt.Errorf("unexpected default transport type")
+	}
+	if c.maxIdleConns, err = getInt(config, "max_idle_conns", defaults.MaxIdleConnsPerHost); err != nil {
+		return nil, err
+	} else if c.maxIdleConns < 1 {
+		return nil, fmt.Errorf("max_idle_conns must be positive")
+	}
+	if c.idleConnTimeout, err = getDuration(config, "idle_conn_timeout", defaults.IdleConnTimeout); err != nil {
+		return nil, err
+	}
+	if c.maxRetries, err = getInt(config, "max_retries", defaultMaxRetries); err != nil {
+		return nil, err
+	} else if c.maxRetries < 0 {
+		return nil, fmt.Errorf("max_retries must not be negative")
+	}
+
+	if c.assumeRoleDuration, err = getDuration(config, "assume_role_duration", time.Hour); err != nil {
+		return nil, err
+	}
+	// STS rejects durations outside of 15 minutes to 12 hours.
+	if c.roleARN != "" && (c.assumeRoleDuration < 15*time.Minute || c.assumeRoleDuration > 12*time.Hour) {
+		return nil, fmt.Errorf("assume_role_duration must be between 15m and 12h")
+	}
+	return c, nil
+}
+
+// policyAdminConfig returns a copy of the config using admin_username and
+// admin_password as credentials, or nil if they aren't set.
+func (c *minioConfig) policyAdminConfig() *minioConfig {
+	if c.adminAccessKey == "" {
+		return nil
+	}
+	adminConfig := *c
+	adminConfig.accessKey = c.adminAccessKey
+	adminConfig.secretKey = c.adminSecretKey
+	return &adminConfig
+}
+
+// resolved returns the non-sensitive connection details the config resolves
+// to, after defaults and aliases are applied.
+func (c *minioConfig) resolved() map[string]interface{} {
+	// The config is sent to vault as a protobuf struct, which only takes
+	// untyped lists.
+	hosts := []interface{}{}
+	for _, endpoint := range c.endpoints {
+		hosts = append(hosts, endpoint.Host)
+	}
+	region := c.region
+	if region == "" {
+		region = defaultRegion
+	}
+	return map[string]interface{}{
+		"hosts":           hosts,
+		"scheme":          c.endpoints[0].Scheme,
+		"path_prefix":     c.endpoints[0].Path,
+		"region":          region,
+		"custom_ca":       c.caPool != nil,
+		"tls_skip_verify": c.skipVerify,
+		"assume_role":     c.roleARN != "",
+		"policy_admin":    c.adminAccessKey != "",
+	}
+}
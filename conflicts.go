@@ -0,0 +1,91 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+
+	madmin "github.com/minio/madmin-go"
+	"github.com/minio/pkg/bucket/policy"
+	iampolicy "github.com/minio/pkg/iam/policy"
+)
+
+// checkDenyConflicts rejects policy lists in which an Allow statement is
+// fully covered by an unconditional Deny of the same or another policy, if
+// check_deny_conflicts is set. Minio merges all attached policies and an
+// explicit deny always wins, so such a grant never has any effect. The check
+// is best effort: conditions, NotAction and group policies are not evaluated.
+// documents holds the documents already at hand, the others are fetched.
+func (minio *Minio) checkDenyConflicts(ctx context.Context, client *madmin.AdminClient, policyList []string, documents map[string]*iampolicy.Policy) error {
+	if !minio.checkDenyConflictsEnabled {
+		return nil
+	}
+	for _, name := range policyList {
+		if documents[name] != nil {
+			continue
+		}
+		data, err := client.InfoCannedPolicy(ctx, name)
+		if madmin.ToErrorResponse(err).Code == "XMinioAdminNoSuchPolicy" {
+			// Only possible with allow_missing_policies, there is nothing
+			// to check yet.
+			continue
+		} else if err != nil {
+			return err
+		}
+		document, err := iampolicy.ParseConfig(bytes.NewReader(data))
+		if err != nil {
+			return fmt.Errorf("invalid policy %q: %w", name, err)
+		}
+		documents[name] = document
+	}
+
+	for _, allowName := range policyList {
+		allowPolicy := documents[allowName]
+		if allowPolicy == nil {
+			continue
+		}
+		for i, allow := range allowPolicy.Statements {
+			if allow.Effect != policy.Allow || len(allow.NotActions) > 0 {
+				continue
+			}
+			for _, denyName := range policyList {
+				denyPolicy := documents[denyName]
+				if denyPolicy == nil {
+					continue
+				}
+				for _, deny := range denyPolicy.Statements {
+					if denyCovers(deny, allow) {
+						return fmt.Errorf("statement %d of policy %q is nullified by a Deny in policy %q", i, allowName, denyName)
+					}
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// denyCovers reports whether deny unconditionally denies every action on
+// every resource allow grants.
+func denyCovers(deny, allow iampolicy.Statement) bool {
+	if deny.Effect != policy.Deny || len(deny.NotActions) > 0 || len(deny.Conditions) > 0 {
+		return false
+	}
+	for action := range allow.Actions {
+		if !deny.Actions.Match(action) {
+			return false
+		}
+	}
+	// Admin statements have no resources, the actions alone decide.
+	if len(deny.Resources) == 0 {
+		return true
+	}
+	if len(allow.Resources) == 0 {
+		return false
+	}
+	for resource := range allow.Resources {
+		if !deny.Resources.MatchResource(resource.Pattern) {
+			return false
+		}
+	}
+	return true
+}
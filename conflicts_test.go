@@ -0,0 +1,46 @@
+package main
+
+import (
+	"strings"
+	"testing"
+
+	iampolicy "github.com/minio/pkg/iam/policy"
+)
+
+// parseStatement parses a single policy statement given as JSON.
+func parseStatement(t *testing.T, statement string) iampolicy.Statement {
+	t.Helper()
+	document, err := iampolicy.ParseConfig(strings.NewReader(`{"Version":"2012-10-17","Statement":[` + statement + `]}`))
+	if err != nil {
+		t.Fatalf("invalid statement %s: %v", statement, err)
+	}
+	return document.Statements[0]
+}
+
+func TestDenyCovers(t *testing.T) {
+	allowGet := `{"Effect":"Allow","Action":["s3:GetObject"],"Resource":["arn:aws:s3:::bucket/*"]}`
+	tests := []struct {
+		name  string
+		deny  string
+		allow string
+		want  bool
+	}{
+		{"same action and resource", `{"Effect":"Deny","Action":["s3:GetObject"],"Resource":["arn:aws:s3:::bucket/*"]}`, allowGet, true},
+		{"wildcard action", `{"Effect":"Deny","Action":["s3:*"],"Resource":["arn:aws:s3:::bucket/*"]}`, allowGet, true},
+		{"wildcard resource", `{"Effect":"Deny","Action":["s3:GetObject"],"Resource":["arn:aws:s3:::*"]}`, allowGet, true},
+		{"other action", `{"Effect":"Deny","Action":["s3:PutObject"],"Resource":["arn:aws:s3:::bucket/*"]}`, allowGet, false},
+		{"narrower resource", `{"Effect":"Deny","Action":["s3:GetObject"],"Resource":["arn:aws:s3:::bucket/private/*"]}`, allowGet, false},
+		{"some of the actions", `{"Effect":"Deny","Action":["s3:GetObject"],"Resource":["arn:aws:s3:::*"]}`, `{"Effect":"Allow","Action":["s3:GetObject","s3:PutObject"],"Resource":["arn:aws:s3:::bucket/*"]}`, false},
+		{"conditional deny", `{"Effect":"Deny","Action":["s3:*"],"Resource":["arn:aws:s3:::*"],"Condition":{"Bool":{"aws:SecureTransport":["false"]}}}`, allowGet, false},
+		{"not action deny", `{"Effect":"Deny","NotAction":["s3:PutObject"],"Resource":["arn:aws:s3:::*"]}`, allowGet, false},
+		{"allow is no deny", allowGet, allowGet, false},
+		{"admin actions", `{"Effect":"Deny","Action":["admin:*"]}`, `{"Effect":"Allow","Action":["admin:ServerInfo"]}`, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := denyCovers(parseStatement(t, tt.deny), parseStatement(t, tt.allow)); got != tt.want {
+				t.Errorf("denyCovers(%s, %s) = %v, want %v", tt.deny, tt.allow, got, tt.want)
+			}
+		})
+	}
+}
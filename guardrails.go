@@ -0,0 +1,67 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/minio/pkg/bucket/policy"
+	iampolicy "github.com/minio/pkg/iam/policy"
+	"github.com/minio/pkg/wildcard"
+)
+
+// checkDeniedGrants rejects policy documents with an Allow statement that
+// grants one of the denied_actions on one of the denied_resources. Without
+// denied_resources the actions are denied on every resource. A statement
+// grants a denied pattern if its own patterns cover it: "s3:*" is caught by
+// a denied "s3:DeleteBucket", but "s3:GetObject" is not by a denied "s3:*".
+func (minio *Minio) checkDeniedGrants(name string, document *iampolicy.Policy) error {
+	if len(minio.deniedActions) == 0 {
+		return nil
+	}
+	for i, statement := range document.Statements {
+		if statement.Effect != policy.Allow {
+			continue
+		}
+		for _, action := range minio.deniedActions {
+			if !grantsAction(statement, action) {
+				continue
+			}
+			if resource, ok := minio.grantsDeniedResource(statement); ok {
+				return fmt.Errorf("statement %d of policy %q grants denied action %q on %q", i, name, action, resource)
+			}
+		}
+	}
+	return nil
+}
+
+// grantsAction reports whether an Allow statement grants every action the
+// denied pattern matches.
+func grantsAction(statement iampolicy.Statement, denied string) bool {
+	if len(statement.NotActions) > 0 {
+		// NotAction grants everything that isn't listed.
+		return !statement.NotActions.Match(iampolicy.Action(denied))
+	}
+	for action := range statement.Actions {
+		if wildcard.Match(string(action), denied) {
+			return true
+		}
+	}
+	return false
+}
+
+// grantsDeniedResource returns the first resource of the statement covering
+// one of the denied_resources. Statements without resources, like admin
+// ones, apply to every resource.
+func (minio *Minio) grantsDeniedResource(statement iampolicy.Statement) (string, bool) {
+	if len(minio.deniedResources) == 0 || len(statement.Resources) == 0 {
+		return "*", true
+	}
+	for resource := range statement.Resources {
+		arn := resource.String()
+		for _, denied := range minio.deniedResources {
+			if wildcard.Match(arn, denied) {
+				return arn, true
+			}
+		}
+	}
+	return "", false
+}
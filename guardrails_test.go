@@ -0,0 +1,26 @@
+package main
+
+import "testing"
+
+func TestGrantsAction(t *testing.T) {
+	tests := []struct {
+		name      string
+		statement string
+		denied    string
+		want      bool
+	}{
+		{"exact action", `{"Effect":"Allow","Action":["s3:DeleteBucket"],"Resource":["arn:aws:s3:::*"]}`, "s3:DeleteBucket", true},
+		{"wildcard action", `{"Effect":"Allow","Action":["s3:*"],"Resource":["arn:aws:s3:::*"]}`, "s3:DeleteBucket", true},
+		{"narrower action", `{"Effect":"Allow","Action":["s3:GetObject"],"Resource":["arn:aws:s3:::*"]}`, "s3:*", false},
+		{"other action", `{"Effect":"Allow","Action":["s3:GetObject"],"Resource":["arn:aws:s3:::*"]}`, "s3:DeleteBucket", false},
+		{"not action without it", `{"Effect":"Allow","NotAction":["s3:GetObject"],"Resource":["arn:aws:s3:::*"]}`, "s3:DeleteBucket", true},
+		{"not action with it", `{"Effect":"Allow","NotAction":["s3:DeleteBucket"],"Resource":["arn:aws:s3:::*"]}`, "s3:DeleteBucket", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := grantsAction(parseStatement(t, tt.statement), tt.denied); got != tt.want {
+				t.Errorf("grantsAction(%s, %q) = %v, want %v", tt.statement, tt.denied, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,42 @@
+package main
+
+import (
+	"os"
+	"time"
+
+	metrics "github.com/armon/go-metrics"
+)
+
+// statsdAddrEnv names the environment variable holding the statsd address
+// metrics are sent to. The plugin runs in its own process, so it can't use
+// the metrics sink of the vault server.
+const statsdAddrEnv = "VAULT_PLUGIN_MINIO_STATSD_ADDR"
+
+// setupMetrics installs a statsd sink if one is configured. Without it the
+// metrics calls below are no-ops.
+func setupMetrics() error {
+	addr := os.Getenv(statsdAddrEnv)
+	if addr == "" {
+		return nil
+	}
+	sink, err := metrics.NewStatsdSink(addr)
+	if err != nil {
+		return err
+	}
+	config := metrics.DefaultConfig("vault-plugin-database-minio")
+	config.EnableRuntimeMetrics = false
+	_, err = metrics.NewGlobal(config, sink)
+	return err
+}
+
+// recordOperation emits the outcome and latency of a plugin operation. err
+// is a pointer so it can be deferred with a named result.
+func recordOperation(operation string, start time.Time, err *error) {
+	labels := []metrics.Label{{Name: "operation", Value: operation}}
+	metrics.MeasureSinceWithLabels([]string{"minio", "operation", "duration"}, start, labels)
+	if *err != nil {
+		metrics.IncrCounterWithLabels([]string{"minio", "operation", "error"}, 1, labels)
+	} else {
+		metrics.IncrCounterWithLabels([]string{"minio", "operation", "success"}, 1, labels)
+	}
+}
@@ -0,0 +1,197 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/template"
+	"time"
+	"unicode"
+
+	iampolicy "github.com/minio/pkg/iam/policy"
+)
+
+// maxPolicySourceSize limits policy documents loaded from files or urls.
+const maxPolicySourceSize = 1 << 20
+
+// policyURLClient fetches PolicyURL documents. Its timeout bounds a stalled
+// server even without a request_timeout.
+var policyURLClient = &http.Client{Timeout: 30 * time.Second}
+
+// errPolicyFileOutside is returned for PolicyFile paths leaving policy_dir.
+var errPolicyFileOutside = errors.New("PolicyFile must be a relative path within policy_dir")
+
+// loadPolicy returns the policy document of an EnsurePolicy entry, which is
+// given inline as Policy, as PolicyFile relative to the policy_dir config
+// value, as https PolicyURL or as Template from policy_templates.
+func (minio *Minio) loadPolicy(ctx context.Context, policy EnsurePolicyStatement) (*iampolicy.Policy, error) {
+	sources := 0
+	for _, set := range []bool{policy.Policy != nil, policy.PolicyFile != "", policy.PolicyURL != "", policy.Template != ""} {
+		if set {
+			sources++
+		}
+	}
+	if sources == 0 {
+		return nil, fmt.Errorf("policy %q has no Policy document", policy.Name)
+	} else if sources > 1 {
+		return nil, fmt.Errorf("policy %q must set only one of Policy, PolicyFile, PolicyURL and Template", policy.Name)
+	}
+	if len(policy.Parameters) > 0 && policy.Template == "" {
+		return nil, fmt.Errorf("policy %q: Parameters require a Template", policy.Name)
+	}
+
+	var reader io.Reader
+	if policy.Policy != nil {
+		return policy.Policy, nil
+	} else if policy.Template != "" {
+		rendered, err := minio.renderPolicyTemplate(policy)
+		if err != nil {
+			return nil, err
+		}
+		reader = strings.NewReader(rendered)
+	} else if policy.PolicyFile != "" {
+		if minio.policyDir == "" {
+			return nil, fmt.Errorf("policy %q: PolicyFile requires policy_dir to be configured", policy.Name)
+		}
+		path, err := resolvePolicyFile(minio.policyDir, policy.PolicyFile)
+		if err != nil {
+			return nil, fmt.Errorf("policy %q: %w", policy.Name, err)
+		}
+		f, err := os.Open(path)
+		if err != nil {
+			return nil, fmt.Errorf("policy %q: %w", policy.Name, err)
+		}
+		defer f.Close()
+		reader = f
+	} else {
+		parsed, err := url.Parse(policy.PolicyURL)
+		if err != nil {
+			return nil, fmt.Errorf("policy %q: invalid PolicyURL: %w", policy.Name, err)
+		}
+		if parsed.Scheme != "https" {
+			return nil, fmt.Errorf("policy %q: PolicyURL must use https", policy.Name)
+		}
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, parsed.String(), nil)
+		if err != nil {
+			return nil, err
+		}
+		resp, err := policyURLClient.Do(req)
+		if err != nil {
+			return nil, fmt.Errorf("policy %q: failed to fetch PolicyURL: %w", policy.Name, err)
+		}
+		defer resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			return nil, fmt.Errorf("policy %q: failed to fetch PolicyURL: %s", policy.Name, resp.Status)
+		}
+		reader = resp.Body
+	}
+
+	document, err := iampolicy.ParseConfig(io.LimitReader(reader, maxPolicySourceSize))
+	if err != nil {
+		return nil, fmt.Errorf("invalid policy %q: %w", policy.Name, err)
+	}
+	return document, nil
+}
+
+// resolvePolicyFile returns the path of a PolicyFile below dir. Only files
+// below policy_dir may be read, statements must not be able to point at
+// arbitrary files of the vault host, so symlinks are resolved before the
+// path is checked to stay within dir.
+func resolvePolicyFile(dir, file string) (string, error) {
+	if !filepath.IsLocal(file) {
+		return "", errPolicyFileOutside
+	}
+	root, err := filepath.EvalSymlinks(dir)
+	if err != nil {
+		return "", err
+	}
+	resolved, err := filepath.EvalSymlinks(filepath.Join(root, file))
+	if err != nil {
+		return "", err
+	}
+	if rel, err := filepath.Rel(root, resolved); err != nil || !filepath.IsLocal(rel) {
+		return "", errPolicyFileOutside
+	}
+	return resolved, nil
+}
+
+// policyCatalog holds the parsed policy_templates by name.
+type policyCatalog map[string]*template.Template
+
+// parsePolicyTemplates parses the policy_templates config value, a map of
+// template names to policy documents with placeholders like {{.Bucket}}. It
+// may be given as a map or as a JSON object string, and the documents as
+// objects or JSON strings.
+func parsePolicyTemplates(config map[string]interface{}) (policyCatalog, error) {
+	raw, ok := config["policy_templates"]
+	if !ok || raw == nil || raw == "" {
+		return nil, nil
+	}
+	values := map[string]interface{}{}
+	switch raw := raw.(type) {
+	case map[string]interface{}:
+		values = raw
+	case string:
+		if err := json.Unmarshal([]byte(raw), &values); err != nil {
+			return nil, fmt.Errorf("policy_templates must be a JSON object: %w", err)
+		}
+	default:
+		return nil, fmt.Errorf("policy_templates must be a map of policy documents")
+	}
+	templates := policyCatalog{}
+	for name, value := range values {
+		document, ok := value.(string)
+		if !ok {
+			data, err := json.Marshal(value)
+			if err != nil {
+				return nil, fmt.Errorf("invalid policy template %q: %w", name, err)
+			}
+			document = string(data)
+		}
+		// Missing parameters must not silently end up as "<no value>" in
+		// the policy.
+		tmpl, err := template.New(name).Option("missingkey=error").Parse(document)
+		if err != nil {
+			return nil, fmt.Errorf("invalid policy template %q: %w", name, err)
+		}
+		templates[name] = tmpl
+	}
+	return templates, nil
+}
+
+// renderPolicyTemplate renders the policy_templates entry an EnsurePolicy
+// entry refers to with its Parameters. {{.Username}} is kept for
+// renderPolicyDocument, which also makes sure the policy is per-user then.
+func (minio *Minio) renderPolicyTemplate(policy EnsurePolicyStatement) (string, error) {
+	tmpl, ok := minio.policyTemplates[policy.Template]
+	if !ok {
+		return "", fmt.Errorf("policy %q: unknown policy template %q", policy.Name, policy.Template)
+	}
+	params := map[string]string{"Username": "{{.Username}}"}
+	for k, v := range policy.Parameters {
+		if k == "Username" {
+			return "", fmt.Errorf("policy %q: Username can not be set as a parameter", policy.Name)
+		}
+		if strings.Contains(v, "{{") {
+			return "", fmt.Errorf("policy %q: parameter %q must not contain templates", policy.Name, k)
+		}
+		// Parameters end up in JSON strings as they are, so they must not
+		// need escaping there, or they could add to the document.
+		if strings.ContainsAny(v, `"\`) || strings.IndexFunc(v, unicode.IsControl) >= 0 {
+			return "", fmt.Errorf("policy %q: parameter %q must not contain quotes, backslashes or control characters", policy.Name, k)
+		}
+		params[k] = v
+	}
+	rendered := &strings.Builder{}
+	if err := tmpl.Execute(rendered, params); err != nil {
+		return "", fmt.Errorf("policy %q: failed to render policy template %q: %w", policy.Name, policy.Template, err)
+	}
+	return rendered.String(), nil
+}
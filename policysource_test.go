@@ -0,0 +1,149 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestParsePolicyTemplates(t *testing.T) {
+	document := `{"Version":"2012-10-17","Statement":[{"Effect":"Allow","Action":["s3:*"],"Resource":["arn:aws:s3:::{{.Bucket}}/*"]}]}`
+	tests := []struct {
+		name    string
+		config  map[string]interface{}
+		want    []string
+		wantErr string
+	}{
+		{name: "unset", config: map[string]interface{}{}},
+		{name: "empty string", config: map[string]interface{}{"policy_templates": ""}},
+		{name: "map of strings", config: map[string]interface{}{"policy_templates": map[string]interface{}{"bucket": document}}, want: []string{"bucket"}},
+		{name: "map of objects", config: map[string]interface{}{"policy_templates": map[string]interface{}{"bucket": map[string]interface{}{"Version": "2012-10-17"}}}, want: []string{"bucket"}},
+		{name: "JSON string", config: map[string]interface{}{"policy_templates": `{"a":"{}","b":{}}`}, want: []string{"a", "b"}},
+		{name: "invalid JSON", config: map[string]interface{}{"policy_templates": "{"}, wantErr: "policy_templates must be a JSON object"},
+		{name: "invalid type", config: map[string]interface{}{"policy_templates": 1}, wantErr: "policy_templates must be a map of policy documents"},
+		{name: "invalid template", config: map[string]interface{}{"policy_templates": map[string]interface{}{"bad": "{{.Bucket"}}, wantErr: `invalid policy template "bad"`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			templates, err := parsePolicyTemplates(tt.config)
+			if tt.wantErr != "" {
+				if err == nil || !strings.HasPrefix(err.Error(), tt.wantErr) {
+					t.Errorf("parsePolicyTemplates() error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("parsePolicyTemplates(): %v", err)
+			}
+			if len(templates) != len(tt.want) {
+				t.Errorf("parsePolicyTemplates() returned %d templates, want %d", len(templates), len(tt.want))
+			}
+			for _, name := range tt.want {
+				if templates[name] == nil {
+					t.Errorf("parsePolicyTemplates() is missing template %q", name)
+				}
+			}
+		})
+	}
+}
+
+func TestRenderPolicyTemplate(t *testing.T) {
+	templates, err := parsePolicyTemplates(map[string]interface{}{"policy_templates": map[string]interface{}{
+		"bucket": `{"Resource":["arn:aws:s3:::{{.Bucket}}/{{.Username}}/*"]}`,
+	}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	minio := &Minio{policyTemplates: templates}
+	tests := []struct {
+		name       string
+		template   string
+		parameters map[string]string
+		want       string
+		wantErr    string
+	}{
+		{name: "rendered", template: "bucket", parameters: map[string]string{"Bucket": "data"}, want: `{"Resource":["arn:aws:s3:::data/{{.Username}}/*"]}`},
+		{name: "unknown template", template: "other", wantErr: `policy "p": unknown policy template "other"`},
+		{name: "missing parameter", template: "bucket", wantErr: `policy "p": failed to render policy template "bucket"`},
+		{name: "username parameter", template: "bucket", parameters: map[string]string{"Bucket": "data", "Username": "x"}, wantErr: `policy "p": Username can not be set as a parameter`},
+		{name: "template parameter", template: "bucket", parameters: map[string]string{"Bucket": "{{.Username}}"}, wantErr: `policy "p": parameter "Bucket" must not contain templates`},
+		{name: "quote", template: "bucket", parameters: map[string]string{"Bucket": `data"],"Action":["*`}, wantErr: `policy "p": parameter "Bucket" must not contain quotes`},
+		{name: "backslash", template: "bucket", parameters: map[string]string{"Bucket": `data\`}, wantErr: `policy "p": parameter "Bucket" must not contain quotes`},
+		{name: "control character", template: "bucket", parameters: map[string]string{"Bucket": "data\n"}, wantErr: `policy "p": parameter "Bucket" must not contain quotes`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := minio.renderPolicyTemplate(EnsurePolicyStatement{Name: "p", Template: tt.template, Parameters: tt.parameters})
+			if tt.wantErr != "" {
+				if err == nil || !strings.HasPrefix(err.Error(), tt.wantErr) {
+					t.Errorf("renderPolicyTemplate() error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("renderPolicyTemplate(): %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("renderPolicyTemplate() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestResolvePolicyFile(t *testing.T) {
+	base := t.TempDir()
+	dir := filepath.Join(base, "policies")
+	for _, d := range []string{dir, filepath.Join(dir, "team")} {
+		if err := os.Mkdir(d, 0o755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, f := range []string{filepath.Join(dir, "team", "read.json"), filepath.Join(base, "secret.json")} {
+		if err := os.WriteFile(f, []byte("{}"), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for link, target := range map[string]string{
+		filepath.Join(dir, "inside.json"):  filepath.Join("team", "read.json"),
+		filepath.Join(dir, "outside.json"): filepath.Join(base, "secret.json"),
+		filepath.Join(dir, "parent"):       base,
+		filepath.Join(base, "linked"):      dir,
+	} {
+		if err := os.Symlink(target, link); err != nil {
+			t.Fatal(err)
+		}
+	}
+	want, err := filepath.EvalSymlinks(filepath.Join(dir, "team", "read.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		dir     string
+		file    string
+		wantErr bool
+	}{
+		{dir: dir, file: "team/read.json"},
+		{dir: dir, file: "inside.json"},
+		{dir: filepath.Join(base, "linked"), file: "team/read.json"},
+		{dir: dir, file: "../secret.json", wantErr: true},
+		{dir: dir, file: filepath.Join(base, "secret.json"), wantErr: true},
+		{dir: dir, file: "outside.json", wantErr: true},
+		{dir: dir, file: "parent/secret.json", wantErr: true},
+		{dir: dir, file: "missing.json", wantErr: true},
+	}
+	for _, tt := range tests {
+		got, err := resolvePolicyFile(tt.dir, tt.file)
+		if tt.wantErr {
+			if err == nil {
+				t.Errorf("resolvePolicyFile(%q, %q) = %q, want an error", tt.dir, tt.file, got)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("resolvePolicyFile(%q, %q): %v", tt.dir, tt.file, err)
+		} else if got != want {
+			t.Errorf("resolvePolicyFile(%q, %q) = %q, want %q", tt.dir, tt.file, got, want)
+		}
+	}
+}
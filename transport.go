@@ -0,0 +1,166 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"sync/atomic"
+	"time"
+
+	"github.com/minio/minio-go/v7/pkg/credentials"
+	"github.com/minio/minio-go/v7/pkg/signer"
+)
+
+// regionTransport re-signs admin requests for a specific region. madmin
+// always signs for the default region, which gateways configured for another
+// region reject.
+type regionTransport struct {
+	next   http.RoundTripper
+	creds  *credentials.Credentials
+	region string
+}
+
+func (t *regionTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	value, err := t.creds.Get()
+	if err != nil {
+		return nil, err
+	}
+	signed := req.Clone(req.Context())
+	signed.Header.Del("Authorization")
+	signed = signer.SignV4(*signed, value.AccessKeyID, value.SecretAccessKey, value.SessionToken, t.region)
+	return t.next.RoundTrip(signed)
+}
+
+// headerTransport adds the extra_headers to requests. Like the path prefix
+// they are added after signing, so a proxy may strip them before passing the
+// request on to minio.
+type headerTransport struct {
+	next    http.RoundTripper
+	headers http.Header
+}
+
+func (t *headerTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	withHeaders := req.Clone(req.Context())
+	for name, values := range t.headers {
+		withHeaders.Header[name] = values
+	}
+	return t.next.RoundTrip(withHeaders)
+}
+
+// pathPrefixTransport sends requests below the path prefix a reverse proxy
+// exposes minio at. The proxy is expected to strip the prefix again, so it
+// is added after signing: the signature covers the path minio receives.
+type pathPrefixTransport struct {
+	next   http.RoundTripper
+	prefix string
+}
+
+func (t *pathPrefixTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	prefixed := req.Clone(req.Context())
+	prefixed.URL.Path = t.prefix + req.URL.Path
+	if req.URL.RawPath != "" {
+		prefixed.URL.RawPath = t.prefix + req.URL.RawPath
+	}
+	return t.next.RoundTrip(prefixed)
+}
+
+// failoverTransport sends admin requests to the next endpoint when the
+// current one can't be reached, and keeps using the endpoint that worked for
+// later requests. Requests are only redirected, so it has to wrap a
+// regionTransport to sign them for the endpoint they are sent to.
+type failoverTransport struct {
+	next    http.RoundTripper
+	hosts   []string
+	current int32
+}
+
+func (t *failoverTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	start := int(atomic.LoadInt32(&t.current))
+	var err error
+	for i := range t.hosts {
+		index := (start + i) % len(t.hosts)
+		attempt := req.Clone(req.Context())
+		attempt.URL.Host = t.hosts[index]
+		attempt.Host = t.hosts[index]
+		if i > 0 && req.Body != nil && req.Body != http.NoBody {
+			if req.GetBody == nil {
+				return nil, err
+			}
+			if attempt.Body, err = req.GetBody(); err != nil {
+				return nil, err
+			}
+		}
+
+		var resp *http.Response
+		resp, err = t.next.RoundTrip(attempt)
+		if err == nil {
+			atomic.StoreInt32(&t.current, int32(index))
+			return resp, nil
+		}
+		if req.Context().Err() != nil {
+			return nil, err
+		}
+	}
+	return nil, err
+}
+
+const (
+	retryBaseDelay = 200 * time.Millisecond
+	retryMaxDelay  = 5 * time.Second
+)
+
+// retryTransport retries admin requests failing with network errors, 429 or
+// 5xx responses with exponential backoff. Other responses, including 4xx
+// errors like missing users, are returned right away.
+type retryTransport struct {
+	next       http.RoundTripper
+	maxRetries int
+}
+
+func (t *retryTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	delay := retryBaseDelay
+	for attempt := 0; ; attempt++ {
+		if attempt > 0 {
+			if req.GetBody != nil {
+				body, err := req.GetBody()
+				if err != nil {
+					return nil, err
+				}
+				req = req.Clone(req.Context())
+				req.Body = body
+			}
+			select {
+			case <-req.Context().Done():
+				return nil, req.Context().Err()
+			case <-time.After(delay):
+			}
+			if delay *= 2; delay > retryMaxDelay {
+				delay = retryMaxDelay
+			}
+		}
+
+		resp, err := t.next.RoundTrip(req)
+		if attempt >= t.maxRetries || !retryable(req, resp, err) {
+			return resp, err
+		}
+		if resp != nil {
+			io.Copy(ioutil.Discard, resp.Body)
+			resp.Body.Close()
+		}
+	}
+}
+
+// retryable reports whether a request may succeed when sent again.
+func retryable(req *http.Request, resp *http.Response, err error) bool {
+	if req.Body != nil && req.Body != http.NoBody && req.GetBody == nil {
+		// The body has been consumed and can't be sent again.
+		return false
+	}
+	if err != nil {
+		return !errors.Is(err, context.Canceled) && !errors.Is(err, context.DeadlineExceeded)
+	}
+	return resp.StatusCode == http.StatusTooManyRequests ||
+		(resp.StatusCode >= 500 && resp.StatusCode != http.StatusNotImplemented)
+}
@@ -0,0 +1,96 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestRetryable(t *testing.T) {
+	get, _ := http.NewRequest(http.MethodGet, "http://minio/", nil)
+	// NewRequest sets GetBody for bytes readers, so the body can be sent
+	// again.
+	replayable, _ := http.NewRequest(http.MethodPut, "http://minio/", bytes.NewReader([]byte("body")))
+	consumed, _ := http.NewRequest(http.MethodPut, "http://minio/", io.NopCloser(bytes.NewReader([]byte("body"))))
+	tests := []struct {
+		name   string
+		req    *http.Request
+		status int
+		err    error
+		want   bool
+	}{
+		{name: "network error", req: get, err: errors.New("connection refused"), want: true},
+		{name: "canceled", req: get, err: fmt.Errorf("request failed: %w", context.Canceled)},
+		{name: "deadline exceeded", req: get, err: context.DeadlineExceeded},
+		{name: "ok", req: get, status: http.StatusOK},
+		{name: "not found", req: get, status: http.StatusNotFound},
+		{name: "forbidden", req: get, status: http.StatusForbidden},
+		{name: "too many requests", req: get, status: http.StatusTooManyRequests, want: true},
+		{name: "internal error", req: get, status: http.StatusInternalServerError, want: true},
+		{name: "unavailable", req: get, status: http.StatusServiceUnavailable, want: true},
+		{name: "not implemented", req: get, status: http.StatusNotImplemented},
+		{name: "replayable body", req: replayable, status: http.StatusServiceUnavailable, want: true},
+		{name: "consumed body", req: consumed, status: http.StatusServiceUnavailable},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var resp *http.Response
+			if tt.err == nil {
+				resp = &http.Response{StatusCode: tt.status}
+			}
+			if got := retryable(tt.req, resp, tt.err); got != tt.want {
+				t.Errorf("retryable() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+// downHost returns the address of a server that is no longer listening.
+func downHost(t *testing.T) string {
+	t.Helper()
+	server := httptest.NewServer(http.NotFoundHandler())
+	server.Close()
+	return server.Listener.Addr().String()
+}
+
+func TestFailoverTransport(t *testing.T) {
+	bodies := []string{}
+	live := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		bodies = append(bodies, string(body))
+	}))
+	defer live.Close()
+	liveHost := strings.TrimPrefix(live.URL, "http://")
+	transport := &failoverTransport{next: http.DefaultTransport, hosts: []string{downHost(t), liveHost}}
+	client := &http.Client{Transport: transport}
+
+	// The body is sent again to the endpoint failed over to.
+	resp, err := client.Post("http://minio/", "text/plain", strings.NewReader("body"))
+	if err != nil {
+		t.Fatalf("request with the first endpoint down: %v", err)
+	}
+	resp.Body.Close()
+	if transport.current != 1 {
+		t.Errorf("current endpoint = %d, want the live one", transport.current)
+	}
+	// Later requests go to the endpoint that worked right away.
+	resp, err = client.Get("http://minio/")
+	if err != nil {
+		t.Fatalf("request after failing over: %v", err)
+	}
+	resp.Body.Close()
+	if len(bodies) != 2 || bodies[0] != "body" || bodies[1] != "" {
+		t.Errorf("live endpoint received bodies %q, want [\"body\" \"\"]", bodies)
+	}
+
+	down := &http.Client{Transport: &failoverTransport{next: http.DefaultTransport, hosts: []string{downHost(t), downHost(t)}}}
+	if _, err := down.Get("http://minio/"); err == nil {
+		t.Errorf("request with every endpoint down succeeded")
+	}
+}